@@ -0,0 +1,88 @@
+package pipeline
+
+import "encoding/json"
+
+// CommandStep models a command step in a pipeline, the most common step
+// type: it runs a command (or commands) on an agent.
+type CommandStep struct {
+	Command   string            `json:"command,omitempty" yaml:"command,omitempty"`
+	Plugins   Plugins           `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	Env       map[string]string `json:"env,omitempty" yaml:"env,omitempty"`
+	Signature *Signature        `json:"signature,omitempty" yaml:"signature,omitempty"`
+}
+
+// Plugin is a reference to a plugin, optionally with configuration.
+type Plugin struct {
+	Source string `json:"source" yaml:"source"`
+	Config any     `json:"config,omitempty" yaml:"config,omitempty"`
+}
+
+// Plugins is an ordered list of plugins attached to a step.
+type Plugins []*Plugin
+
+func (CommandStep) stepTag() {}
+
+// SignedFields returns the default fields (and values) to sign for a command
+// step: the command, the plugins, and the step's own env (i.e. as if no
+// pipeline-level env were supplied).
+func (c *CommandStep) SignedFields() (map[string]string, error) {
+	return c.signedFieldsWithEnv(nil)
+}
+
+// ValuesForFields looks up the values for a signature's fields, as if no
+// pipeline-level env were supplied.
+func (c *CommandStep) ValuesForFields(fields []string) (map[string]string, error) {
+	return c.valuesForFieldsWithEnv(fields, nil)
+}
+
+func (c *CommandStep) signedFieldsWithEnv(env map[string]string) (map[string]string, error) {
+	return c.valuesForFieldsWithEnv([]string{"command", "env", "plugins"}, env)
+}
+
+func (c *CommandStep) valuesForFieldsWithEnv(fields []string, env map[string]string) (map[string]string, error) {
+	values := make(map[string]string, len(fields))
+
+	for _, field := range fields {
+		switch field {
+		case "command":
+			values["command"] = c.Command
+
+		case "env":
+			b, err := json.Marshal(c.effectiveEnv(env))
+			if err != nil {
+				return nil, err
+			}
+			values["env"] = string(b)
+
+		case "plugins":
+			b, err := json.Marshal(c.Plugins)
+			if err != nil {
+				return nil, err
+			}
+			values["plugins"] = string(b)
+
+		default:
+			return nil, errUnknownSignedField(field)
+		}
+	}
+
+	return values, nil
+}
+
+// effectiveEnv overrides the step's own env with the pipeline-level env
+// (pipeline values win) for the keys the step itself declares. Other callers
+// may pass in a fuller env (e.g. the backend's fully-resolved runtime env at
+// verification time); keys that the step doesn't declare are ignored, so
+// that signing and verification agree regardless of which extra variables
+// happen to be present at each end.
+func (c *CommandStep) effectiveEnv(pipelineEnv map[string]string) map[string]string {
+	merged := make(map[string]string, len(c.Env))
+	for k, v := range c.Env {
+		if pv, ok := pipelineEnv[k]; ok {
+			merged[k] = pv
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,43 @@
+package pipeline
+
+import (
+	"crypto/sha256"
+	"strings"
+)
+
+// Logger receives debug-signing output from WithDebugSigning/
+// WithDebugVerifying. *log.Logger satisfies this.
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// logDebugSigning logs the ordered signed field names, a SHA-256 hash of
+// each field's serialized value, and the final canonical payload - in hex
+// if includeValues is set, otherwise only its length and SHA-256 hash, since
+// the payload contains every field value. That is enough to diagnose a
+// "signature mismatch" between two sides computing the same fields
+// differently, without needing includeValues to see the secret values
+// themselves.
+func logDebugSigning(logger Logger, fields map[string]string, signedFields []string, payload []byte, includeValues bool) {
+	if logger == nil {
+		return
+	}
+
+	logger.Printf("pipeline: signing %d field(s): %s", len(signedFields), strings.Join(signedFields, ", "))
+
+	for _, name := range signedFields {
+		value := fields[name]
+		sum := sha256.Sum256([]byte(value))
+		if includeValues {
+			logger.Printf("pipeline: field %q = %q (sha256 %x)", name, value, sum)
+		} else {
+			logger.Printf("pipeline: field %q (sha256 %x, %d bytes, value redacted)", name, sum, len(value))
+		}
+	}
+
+	if includeValues {
+		logger.Printf("pipeline: canonical payload (%d bytes): %x", len(payload), payload)
+	} else {
+		logger.Printf("pipeline: canonical payload (%d bytes, sha256 %x, value redacted)", len(payload), sha256.Sum256(payload))
+	}
+}
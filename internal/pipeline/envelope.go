@@ -0,0 +1,50 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+)
+
+// EnvelopeType selects the wire format Sign wraps a signature in.
+type EnvelopeType string
+
+const (
+	// EnvelopeJWS is a detached JSON Web Signature (RFC 7515 §A.5): a
+	// protected header carrying alg, kid and the signed field names,
+	// compact-serialized with the payload omitted (the verifier already
+	// has the payload - it recomputes it from the fields being verified).
+	EnvelopeJWS EnvelopeType = "JWS"
+
+	// EnvelopeCOSESign1 is a COSE_Sign1 structure (RFC 8152 §4.2), CBOR
+	// encoded, with a detached payload. Useful for constrained/embedded
+	// runners and interoperability with the wider COSE tooling ecosystem.
+	EnvelopeCOSESign1 EnvelopeType = "COSE_Sign1"
+)
+
+// envelope wraps a canonical field payload and a Signer-produced signature
+// into a Signature's Value (seal), and unwraps + verifies one again (open).
+type envelope interface {
+	seal(ctx context.Context, signedFields []string, payload []byte, signer Signer) (value string, err error)
+	open(ctx context.Context, signedFields []string, payload []byte, value string, verifier Verifier) error
+}
+
+func envelopeFor(t EnvelopeType) (envelope, error) {
+	switch envelopeOrDefault(t) {
+	case EnvelopeJWS:
+		return jwsEnvelope{}, nil
+	case EnvelopeCOSESign1:
+		return coseSign1Envelope{}, nil
+	default:
+		return nil, fmt.Errorf("pipeline: unknown envelope type %q", t)
+	}
+}
+
+// envelopeOrDefault returns t, or EnvelopeJWS if t is empty - so that
+// Signatures computed before EnvelopeType existed (Envelope == "") still
+// verify as the JWS envelope they were always implicitly using.
+func envelopeOrDefault(t EnvelopeType) EnvelopeType {
+	if t == "" {
+		return EnvelopeJWS
+	}
+	return t
+}
@@ -0,0 +1,147 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+// jwsEnvelope implements EnvelopeJWS: a detached compact JWS whose protected
+// header carries alg, kid, crit=["signed_fields"] and signed_fields itself.
+// The header is part of the JWS signing input (ASCII(BASE64URL(header) ||
+// "." || BASE64URL(payload))), so it's covered by the signature even though
+// the payload itself is omitted from the serialized form.
+type jwsEnvelope struct{}
+
+// jwsProtectedHeader is the protected header of a detached JWS produced by
+// jwsEnvelope.
+type jwsProtectedHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid,omitempty"`
+	// Critical lists extensions that a verifier must understand to process
+	// this JWS correctly - here, that it must recompute signed_fields
+	// rather than assume some default set.
+	Critical     []string `json:"crit"`
+	SignedFields []string `json:"signed_fields"`
+	// X5C is the X.509 certificate chain (RFC 7515 §4.1.6), leaf first,
+	// each entry base64-standard-encoded DER, present only when the Signer
+	// is a CertificateChainSigner.
+	X5C []string `json:"x5c,omitempty"`
+	// X5TS256 is the base64url SHA-256 thumbprint of the leaf certificate
+	// (RFC 7515 §4.1.8), a convenience lookup key alongside the full chain.
+	X5TS256 string `json:"x5t#S256,omitempty"`
+}
+
+func (jwsEnvelope) seal(ctx context.Context, signedFields []string, payload []byte, signer Signer) (string, error) {
+	header := jwsProtectedHeader{
+		Algorithm:    signer.Algorithm().String(),
+		KeyID:        signer.KeyID(),
+		Critical:     []string{"signed_fields"},
+		SignedFields: signedFields,
+	}
+
+	if chainSigner, ok := signer.(CertificateChainSigner); ok {
+		chain := chainSigner.CertificateChain()
+		header.X5C = make([]string, len(chain))
+		for i, cert := range chain {
+			header.X5C[i] = base64.StdEncoding.EncodeToString(cert.Raw)
+		}
+		header.X5TS256 = x5tS256(chain[0])
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", fmt.Errorf("encoding JWS protected header: %w", err)
+	}
+	protected := base64URLEncode(headerJSON)
+
+	signingInput := []byte(protected + "." + base64URLEncode(payload))
+
+	sig, err := signer.SignPayload(ctx, signingInput)
+	if err != nil {
+		return "", fmt.Errorf("signing payload: %w", err)
+	}
+
+	return protected + ".." + base64URLEncode(sig), nil
+}
+
+func (jwsEnvelope) open(ctx context.Context, signedFields []string, payload []byte, value string, verifier Verifier) error {
+	protected, sig, ok := splitDetachedCompact(value)
+	if !ok {
+		return fmt.Errorf("malformed JWS: want \"header..signature\"")
+	}
+
+	headerJSON, err := base64URLDecode(protected)
+	if err != nil {
+		return fmt.Errorf("decoding protected header: %w", err)
+	}
+
+	var header jwsProtectedHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return fmt.Errorf("parsing protected header: %w", err)
+	}
+
+	sigBytes, err := base64URLDecode(sig)
+	if err != nil {
+		return fmt.Errorf("decoding signature: %w", err)
+	}
+
+	signingInput := []byte(protected + "." + base64URLEncode(payload))
+	alg := jwa.SignatureAlgorithm(header.Algorithm)
+
+	if len(header.X5C) == 0 {
+		return verifier.VerifyPayload(ctx, signingInput, sigBytes, alg, header.KeyID)
+	}
+
+	chain, err := parseX5C(header.X5C)
+	if err != nil {
+		return fmt.Errorf("parsing x5c certificate chain: %w", err)
+	}
+
+	chainVerifier, ok := verifier.(CertificateChainVerifier)
+	if !ok {
+		return verifier.VerifyPayload(ctx, signingInput, sigBytes, alg, header.KeyID)
+	}
+
+	if err := chainVerifier.VerifyCertificateChain(ctx, chain); err != nil {
+		return fmt.Errorf("validating x5c certificate chain: %w", err)
+	}
+
+	return verifyRaw(chain[0].PublicKey, alg, signingInput, sigBytes)
+}
+
+// parseX5C decodes an x5c header value (RFC 7515 §4.1.6: base64-standard,
+// not base64url, DER certificates) into an X.509 certificate chain, leaf
+// first.
+func parseX5C(x5c []string) ([]*x509.Certificate, error) {
+	chain := make([]*x509.Certificate, len(x5c))
+	for i, entry := range x5c {
+		der, err := base64.StdEncoding.DecodeString(entry)
+		if err != nil {
+			return nil, fmt.Errorf("decoding x5c[%d]: %w", i, err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("parsing x5c[%d]: %w", i, err)
+		}
+		chain[i] = cert
+	}
+	return chain, nil
+}
+
+// splitDetachedCompact splits a "header..signature" compact serialization
+// (i.e. one with an empty, detached payload segment) into its header and
+// signature parts.
+func splitDetachedCompact(value string) (header, sig string, ok bool) {
+	const sep = ".."
+	i := strings.Index(value, sep)
+	if i < 0 {
+		return "", "", false
+	}
+	return value[:i], value[i+len(sep):], true
+}
@@ -0,0 +1,74 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/elliptic"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+func TestSignVerifyCOSE(t *testing.T) {
+	step := &CommandStep{
+		Command: "llamas",
+		Env: map[string]string{
+			"CONTEXT": "cats",
+		},
+	}
+
+	cases := []struct {
+		name           string
+		generateSigner func(alg jwa.SignatureAlgorithm) (jwk.Key, jwk.Set)
+		alg            jwa.SignatureAlgorithm
+	}{
+		{
+			name:           "ECDSA P-256",
+			generateSigner: func(alg jwa.SignatureAlgorithm) (jwk.Key, jwk.Set) { return newECKeyPair(t, alg, elliptic.P256()) },
+			alg:            jwa.ES256,
+		},
+		{
+			name:           "EdDSA Ed25519",
+			generateSigner: func(alg jwa.SignatureAlgorithm) (jwk.Key, jwk.Set) { return newEdwardsKeyPair(t, alg) },
+			alg:            jwa.EdDSA,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			signer, verifier := tc.generateSigner(tc.alg)
+
+			sig, err := Sign(context.Background(), nil, step, NewJWKSigner(signer), WithEnvelope(EnvelopeCOSESign1))
+			if err != nil {
+				t.Fatalf("Sign(CommandStep, signer, WithEnvelope(COSE_Sign1)) error = %v", err)
+			}
+
+			if sig.Envelope != EnvelopeCOSESign1 {
+				t.Errorf("Signature.Envelope = %v, want %v", sig.Envelope, EnvelopeCOSESign1)
+			}
+
+			if err := sig.Verify(context.Background(), nil, step, NewJWKVerifier(verifier)); err != nil {
+				t.Errorf("sig.Verify(CommandStep, verifier) = %v", err)
+			}
+		})
+	}
+}
+
+func TestSignDefaultEnvelopeIsJWS(t *testing.T) {
+	signer, verifier := newSymmetricKeyPair(t, "alpacas", jwa.HS256)
+
+	sig, err := Sign(context.Background(), nil, &CommandStep{Command: "llamas"}, NewJWKSigner(signer))
+	if err != nil {
+		t.Fatalf("Sign() error = %v", err)
+	}
+
+	if sig.Envelope != EnvelopeJWS {
+		t.Errorf("Signature.Envelope = %v, want %v", sig.Envelope, EnvelopeJWS)
+	}
+
+	if err := sig.Verify(context.Background(), nil, &CommandStep{Command: "llamas"}, NewJWKVerifier(verifier)); err != nil {
+		t.Errorf("sig.Verify() = %v", err)
+	}
+}
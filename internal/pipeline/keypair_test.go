@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// newSymmetricKeyPair returns a signer/verifier pair for an HMAC algorithm,
+// both backed by the same shared secret.
+func newSymmetricKeyPair(t *testing.T, secret string, alg jwa.SignatureAlgorithm) (jwk.Key, jwk.Set) {
+	t.Helper()
+
+	key, err := jwk.FromRaw([]byte(secret))
+	if err != nil {
+		t.Fatalf("jwk.FromRaw(secret) error = %v", err)
+	}
+	setCommon(t, key, alg)
+
+	set := jwk.NewSet()
+	if err := set.AddKey(key); err != nil {
+		t.Fatalf("set.AddKey(key) error = %v", err)
+	}
+
+	return key, set
+}
+
+// newRSAKeyPair returns a signer/verifier pair for an RSA algorithm.
+func newRSAKeyPair(t *testing.T, alg jwa.SignatureAlgorithm) (jwk.Key, jwk.Set) {
+	t.Helper()
+
+	raw, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey() error = %v", err)
+	}
+
+	return keyPairFromRaw(t, raw, &raw.PublicKey, alg)
+}
+
+// newECKeyPair returns a signer/verifier pair for an ECDSA algorithm over the
+// given curve.
+func newECKeyPair(t *testing.T, alg jwa.SignatureAlgorithm, curve elliptic.Curve) (jwk.Key, jwk.Set) {
+	t.Helper()
+
+	raw, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+
+	return keyPairFromRaw(t, raw, &raw.PublicKey, alg)
+}
+
+// newEdwardsKeyPair returns a signer/verifier pair for EdDSA (Ed25519).
+func newEdwardsKeyPair(t *testing.T, alg jwa.SignatureAlgorithm) (jwk.Key, jwk.Set) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey() error = %v", err)
+	}
+
+	return keyPairFromRaw(t, priv, pub, alg)
+}
+
+func keyPairFromRaw(t *testing.T, priv, pub any, alg jwa.SignatureAlgorithm) (jwk.Key, jwk.Set) {
+	t.Helper()
+
+	signer, err := jwk.FromRaw(priv)
+	if err != nil {
+		t.Fatalf("jwk.FromRaw(priv) error = %v", err)
+	}
+	setCommon(t, signer, alg)
+
+	verifier, err := jwk.FromRaw(pub)
+	if err != nil {
+		t.Fatalf("jwk.FromRaw(pub) error = %v", err)
+	}
+	setCommon(t, verifier, alg)
+
+	set := jwk.NewSet()
+	if err := set.AddKey(verifier); err != nil {
+		t.Fatalf("set.AddKey(verifier) error = %v", err)
+	}
+
+	return signer, set
+}
+
+func setCommon(t *testing.T, key jwk.Key, alg jwa.SignatureAlgorithm) {
+	t.Helper()
+
+	if err := key.Set(jwk.AlgorithmKey, alg); err != nil {
+		t.Fatalf("key.Set(AlgorithmKey, %v) error = %v", alg, err)
+	}
+	if err := key.Set(jwk.KeyIDKey, t.Name()); err != nil {
+		t.Fatalf("key.Set(KeyIDKey, %v) error = %v", t.Name(), err)
+	}
+}
@@ -0,0 +1,8 @@
+// Package plugin provides pipeline.Signer and pipeline.Verifier
+// implementations backed by out-of-process signing plugins: executables
+// found on PATH by naming convention (buildkite-signing-plugin-<name>)
+// that speak a small JSON-RPC protocol over their stdio. Like the signer
+// package's KMS/HSM adapters, plugins only ever see the canonical payload
+// bytes pipeline.Sign/Signature.Verify already compute, never step
+// internals or key material.
+package plugin
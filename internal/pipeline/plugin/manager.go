@@ -0,0 +1,229 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+)
+
+// binaryPrefix is the naming convention plugins are discovered by: a plugin
+// called "vault" is the executable "buildkite-signing-plugin-vault" on
+// PATH.
+const binaryPrefix = "buildkite-signing-plugin-"
+
+// Manager discovers and runs signing plugins, caching their process
+// handles so a pipeline with many signed steps doesn't fork a fresh plugin
+// process per step.
+type Manager struct {
+	mu    sync.Mutex
+	procs map[string]*process
+
+	// lookPath is exec.LookPath, overridden in tests to find a plugin
+	// binary built into t.TempDir() without mutating PATH.
+	lookPath func(file string) (string, error)
+}
+
+// NewManager returns a Manager that discovers plugins on PATH.
+func NewManager() *Manager {
+	return &Manager{procs: make(map[string]*process), lookPath: exec.LookPath}
+}
+
+// Close terminates every plugin process the Manager has started.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for name, p := range m.procs {
+		if err := p.close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing plugin %q: %w", name, err))
+		}
+		delete(m.procs, name)
+	}
+	return errors.Join(errs...)
+}
+
+// call starts (or reuses) the plugin named name and invokes method on it,
+// decoding its result into result (which may be nil if the caller doesn't
+// need one).
+func (m *Manager) call(ctx context.Context, name, method string, params, result any) error {
+	p, err := m.processFor(name)
+	if err != nil {
+		return err
+	}
+	return p.call(ctx, method, params, result)
+}
+
+func (m *Manager) processFor(name string) (*process, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if p, ok := m.procs[name]; ok {
+		if !p.broken.Load() {
+			return p, nil
+		}
+		// A previous call timed out and killed this process (see
+		// process.call) - evict it so we start a fresh one below, instead
+		// of handing out a process whose stdio no one is reading anymore.
+		delete(m.procs, name)
+	}
+
+	path, err := m.lookPath(binaryPrefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline/plugin: locating plugin %q on PATH: %w", name, err)
+	}
+
+	p, err := startProcess(path)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline/plugin: starting plugin %q: %w", name, err)
+	}
+
+	m.procs[name] = p
+	return p, nil
+}
+
+// process is a running plugin subprocess and the JSON-RPC codec over its
+// stdio. Requests are serialized: a plugin only ever has one call in
+// flight, matching the simple request/response stdio transport.
+type process struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+
+	mu     sync.Mutex
+	nextID int64
+
+	// broken is set once a call times out and kills cmd mid-request, so
+	// that the Manager evicts this process instead of reusing a stdio pipe
+	// no one is reading from anymore (see call).
+	broken atomic.Bool
+}
+
+func startProcess(path string) (*process, error) {
+	cmd := exec.Command(path)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("opening stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("opening stdout: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("starting process: %w", err)
+	}
+
+	return &process{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}, nil
+}
+
+func (p *process) close() error {
+	if err := p.stdin.Close(); err != nil {
+		return err
+	}
+	return p.cmd.Wait()
+}
+
+// rpcRequest and rpcResponse are the JSON-RPC-ish envelope the manager and
+// plugin exchange, one JSON object per line.
+type rpcRequest struct {
+	ID     int64           `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     int64           `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// call sends a single request and waits for its response, respecting ctx's
+// deadline/cancellation - the blocking write+read happens in a goroutine so
+// a plugin that hangs doesn't hang its caller past ctx.
+func (p *process) call(ctx context.Context, method string, params, result any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	id := p.nextID
+
+	paramsJSON, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("encoding %s params: %w", method, err)
+	}
+
+	reqJSON, err := json.Marshal(rpcRequest{ID: id, Method: method, Params: paramsJSON})
+	if err != nil {
+		return fmt.Errorf("encoding %s request: %w", method, err)
+	}
+
+	type outcome struct {
+		resp rpcResponse
+		err  error
+	}
+	done := make(chan outcome, 1)
+
+	go func() {
+		if _, err := p.stdin.Write(append(reqJSON, '\n')); err != nil {
+			done <- outcome{err: fmt.Errorf("writing %s request: %w", method, err)}
+			return
+		}
+		line, err := p.stdout.ReadBytes('\n')
+		if err != nil {
+			done <- outcome{err: fmt.Errorf("reading %s response: %w", method, err)}
+			return
+		}
+		var resp rpcResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			done <- outcome{err: fmt.Errorf("parsing %s response: %w", method, err)}
+			return
+		}
+		done <- outcome{resp: resp}
+	}()
+
+	select {
+	case <-ctx.Done():
+		// The goroutine above may still be blocked writing or reading on
+		// p.stdin/p.stdout. Killing the process unblocks it (the pipes
+		// close under it) and marks the process broken so the Manager
+		// doesn't hand this stdio pair to a later call, which would
+		// otherwise either consume that call's response or have its own
+		// response consumed by this orphaned read.
+		p.broken.Store(true)
+		if p.cmd.Process != nil {
+			_ = p.cmd.Process.Kill()
+		}
+		_ = p.stdin.Close()
+		return fmt.Errorf("pipeline/plugin: %s: %w", method, ctx.Err())
+	case o := <-done:
+		if o.err != nil {
+			return o.err
+		}
+		if o.resp.ID != id {
+			return fmt.Errorf("pipeline/plugin: %s: response id %d does not match request id %d", method, o.resp.ID, id)
+		}
+		if o.resp.Error != nil {
+			return fmt.Errorf("pipeline/plugin: %s: %s", method, o.resp.Error.Message)
+		}
+		if result != nil {
+			if err := json.Unmarshal(o.resp.Result, result); err != nil {
+				return fmt.Errorf("decoding %s result: %w", method, err)
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,147 @@
+package plugin
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildkite/agent/v3/internal/pipeline"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+// testPluginSource is a minimal signing plugin: it signs/verifies with a
+// fixed HMAC-SHA256 secret, enough to exercise the describe/sign-payload/
+// verify-payload RPCs end to end without needing real key management.
+const testPluginSource = `package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+)
+
+var secret = []byte("llama-plugin-secret")
+
+type request struct {
+	ID     int64           ` + "`json:\"id\"`" + `
+	Method string          ` + "`json:\"method\"`" + `
+	Params json.RawMessage ` + "`json:\"params\"`" + `
+}
+
+type response struct {
+	ID     int64       ` + "`json:\"id\"`" + `
+	Result interface{} ` + "`json:\"result,omitempty\"`" + `
+	Error  *rpcError   ` + "`json:\"error,omitempty\"`" + `
+}
+
+type rpcError struct {
+	Code    int    ` + "`json:\"code\"`" + `
+	Message string ` + "`json:\"message\"`" + `
+}
+
+func main() {
+	in := bufio.NewReader(os.Stdin)
+	out := json.NewEncoder(os.Stdout)
+
+	for {
+		line, err := in.ReadBytes('\n')
+		if len(line) == 0 && err != nil {
+			return
+		}
+
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			continue
+		}
+
+		switch req.Method {
+		case "describe":
+			out.Encode(response{ID: req.ID, Result: map[string]string{
+				"algorithm": "HS256",
+				"key_id":    "test-plugin-key",
+			}})
+		case "sign-payload":
+			var params struct{ Payload []byte }
+			json.Unmarshal(req.Params, &params)
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(params.Payload)
+			out.Encode(response{ID: req.ID, Result: map[string][]byte{
+				"signature": mac.Sum(nil),
+			}})
+		case "verify-payload":
+			var params struct {
+				Payload   []byte
+				Signature []byte
+			}
+			json.Unmarshal(req.Params, &params)
+			mac := hmac.New(sha256.New, secret)
+			mac.Write(params.Payload)
+			if hmac.Equal(mac.Sum(nil), params.Signature) {
+				out.Encode(response{ID: req.ID, Result: map[string]string{}})
+			} else {
+				out.Encode(response{ID: req.ID, Error: &rpcError{Code: 1, Message: "bad signature"}})
+			}
+		default:
+			out.Encode(response{ID: req.ID, Error: &rpcError{Code: 2, Message: "unknown method"}})
+		}
+	}
+}
+`
+
+// buildTestPlugin compiles testPluginSource into an executable in
+// t.TempDir() and returns its path.
+func buildTestPlugin(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(src, []byte(testPluginSource), 0o644); err != nil {
+		t.Fatalf("writing plugin source: %v", err)
+	}
+
+	bin := filepath.Join(dir, "buildkite-signing-plugin-test")
+	cmd := exec.Command("go", "build", "-o", bin, src)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building test plugin: %v\n%s", err, out)
+	}
+
+	return bin
+}
+
+func TestSignVerifyPlugin(t *testing.T) {
+	bin := buildTestPlugin(t)
+
+	manager := NewManager()
+	manager.lookPath = func(string) (string, error) { return bin, nil }
+	defer manager.Close()
+
+	ctx := context.Background()
+
+	signer, err := NewSigner(ctx, manager, "test")
+	if err != nil {
+		t.Fatalf("NewSigner() error = %v", err)
+	}
+	if signer.Algorithm() != jwa.HS256 {
+		t.Errorf("signer.Algorithm() = %v, want %v", signer.Algorithm(), jwa.HS256)
+	}
+	if signer.KeyID() != "test-plugin-key" {
+		t.Errorf("signer.KeyID() = %q, want %q", signer.KeyID(), "test-plugin-key")
+	}
+
+	verifier := NewVerifier(manager, "test")
+
+	step := &pipeline.CommandStep{Command: "llamas"}
+
+	sig, err := pipeline.Sign(ctx, nil, step, signer)
+	if err != nil {
+		t.Fatalf("pipeline.Sign(CommandStep, plugin signer) error = %v", err)
+	}
+
+	if err := sig.Verify(ctx, nil, step, verifier); err != nil {
+		t.Errorf("sig.Verify(CommandStep, plugin verifier) = %v", err)
+	}
+}
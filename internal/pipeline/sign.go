@@ -0,0 +1,228 @@
+// Package pipeline models pipelines, steps and their signatures.
+package pipeline
+
+import (
+	"context"
+	"crypto"
+	"encoding/base64"
+	"fmt"
+)
+
+// Signature models a signature (e.g. for signing a pipeline step)
+type Signature struct {
+	Algorithm    string       `json:"algorithm" yaml:"algorithm"`
+	SignedFields []string     `json:"signed_fields" yaml:"signed_fields"`
+	Value        string       `json:"value" yaml:"value"`
+	Envelope     EnvelopeType `json:"envelope,omitempty" yaml:"envelope,omitempty"`
+	// Timestamp, if present, is the raw bytes of an RFC 3161 TimeStampResp
+	// obtained by a TSAClient over Value at signing time. It lets Verify
+	// confirm the signing certificate was valid when the signature was
+	// made even after that certificate has since expired or been rotated
+	// - see WithTimestamp and WithTimestampTrust.
+	Timestamp []byte `json:"timestamp,omitempty" yaml:"timestamp,omitempty"`
+}
+
+// signOptions holds the options Sign accepts, via SignOption.
+type signOptions struct {
+	envelope           EnvelopeType
+	tsaClient          TSAClient
+	tsaHash            crypto.Hash
+	debugLogger        Logger
+	debugIncludeValues bool
+}
+
+// SignOption configures Sign.
+type SignOption func(*signOptions)
+
+// WithEnvelope selects the envelope Sign wraps the signature in. The
+// default, used if this option isn't passed, is EnvelopeJWS.
+func WithEnvelope(t EnvelopeType) SignOption {
+	return func(o *signOptions) { o.envelope = t }
+}
+
+// WithTimestamp has Sign request an RFC 3161 timestamp over the computed
+// signature from client, hashed with hash (crypto.SHA256 if zero), and
+// attach it to the returned Signature's Timestamp field.
+func WithTimestamp(client TSAClient, hash crypto.Hash) SignOption {
+	return func(o *signOptions) { o.tsaClient = client; o.tsaHash = hash }
+}
+
+// WithDebugSigning logs the ordered signed field names, a SHA-256 hash of
+// each field's value, and a SHA-256 hash of the final canonical payload to
+// logger before computing the signature - useful for diagnosing "signature
+// mismatch" errors between the agent and a backend independently
+// recomputing the same fields. Field values (and the payload, which contains
+// them) are redacted by default; pass WithDebugSigningIncludeValues too to
+// log them in full, the payload in hex.
+func WithDebugSigning(logger Logger) SignOption {
+	return func(o *signOptions) { o.debugLogger = logger }
+}
+
+// WithDebugSigningIncludeValues has WithDebugSigning log each field's raw
+// value alongside its hash, instead of just the hash. Only pass this
+// somewhere that log output won't leak secrets - step env values included.
+func WithDebugSigningIncludeValues() SignOption {
+	return func(o *signOptions) { o.debugIncludeValues = true }
+}
+
+// Sign computes a signature over sf, covering the fields sf itself chooses
+// (via SignedFields, or signedFieldsWithEnv if sf is an EnvSignedFielder),
+// and asks signer to sign the resulting canonical payload, wrapped in the
+// envelope selected by opts (EnvelopeJWS by default). signer never needs to
+// expose key material to do this - see Signer.
+//
+// env is the pipeline-level environment in effect when sf was uploaded; for
+// step types whose signed fields depend on env (such as CommandStep's "env"
+// field) it is merged in before signing.
+//
+// ctx is threaded through to signer, for backends where signing involves a
+// remote call (a KMS, an HSM, a signing plugin) that should respect
+// cancellation and deadlines.
+func Sign(ctx context.Context, env map[string]string, sf SignedFielder, signer Signer, opts ...SignOption) (*Signature, error) {
+	var o signOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.envelope == "" {
+		o.envelope = EnvelopeJWS
+	}
+
+	envl, err := envelopeFor(o.envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := fieldsToSign(sf, env)
+	if err != nil {
+		return nil, fmt.Errorf("computing fields to sign: %w", err)
+	}
+
+	signedFields := sortedFieldNames(fields)
+	payload := canonicalPayload(fields)
+
+	logDebugSigning(o.debugLogger, fields, signedFields, payload, o.debugIncludeValues)
+
+	value, err := envl.seal(ctx, signedFields, payload, signer)
+	if err != nil {
+		return nil, fmt.Errorf("sealing %s envelope: %w", o.envelope, err)
+	}
+
+	sig := &Signature{
+		Algorithm:    signer.Algorithm().String(),
+		SignedFields: signedFields,
+		Value:        value,
+		Envelope:     o.envelope,
+	}
+
+	if o.tsaClient != nil {
+		hash := o.tsaHash
+		if hash == 0 {
+			hash = crypto.SHA256
+		}
+		token, err := o.tsaClient.Timestamp(ctx, []byte(value), hash)
+		if err != nil {
+			return nil, fmt.Errorf("requesting RFC 3161 timestamp: %w", err)
+		}
+		sig.Timestamp = token
+	}
+
+	return sig, nil
+}
+
+// verifyOptions holds the options Verify accepts, via VerifyOption.
+type verifyOptions struct {
+	tsaPolicy          *TimestampTrustPolicy
+	debugLogger        Logger
+	debugIncludeValues bool
+}
+
+// VerifyOption configures Signature.Verify.
+type VerifyOption func(*verifyOptions)
+
+// WithTimestampTrust has Verify validate sig's RFC 3161 Timestamp against
+// policy and, if it checks out, use the timestamp's genTime rather than the
+// current time when judging whether an x5c signing certificate was valid -
+// so a signature made with a certificate that has since expired or been
+// rotated still verifies. It's an error to pass this if sig has no
+// Timestamp.
+func WithTimestampTrust(policy TimestampTrustPolicy) VerifyOption {
+	return func(o *verifyOptions) { o.tsaPolicy = &policy }
+}
+
+// WithDebugVerifying logs the ordered signed field names, a SHA-256 hash of
+// each field's recomputed value, and a SHA-256 hash of the final canonical
+// payload to logger before checking the signature - the verify-side
+// counterpart of WithDebugSigning, for comparing against what the signer
+// logged. Field values (and the payload, which contains them) are redacted
+// by default; pass WithDebugVerifyingIncludeValues too to log them in full,
+// the payload in hex.
+func WithDebugVerifying(logger Logger) VerifyOption {
+	return func(o *verifyOptions) { o.debugLogger = logger }
+}
+
+// WithDebugVerifyingIncludeValues has WithDebugVerifying log each field's
+// raw value alongside its hash, instead of just the hash. Only pass this
+// somewhere that log output won't leak secrets - step env values included.
+func WithDebugVerifyingIncludeValues() VerifyOption {
+	return func(o *verifyOptions) { o.debugIncludeValues = true }
+}
+
+// Verify verifies sig against sf using env (the verifier's view of the
+// runtime environment the step ran, or will run, with) and verifier, which
+// checks the signature against whatever keys it trusts. The envelope used
+// is whichever Sign recorded on the Signature (EnvelopeJWS if unset, for
+// signatures produced before EnvelopeType existed).
+//
+// ctx is threaded through to verifier, for backends where verification
+// involves a remote call (a KMS, a remote JWKS fetch) that should respect
+// cancellation and deadlines.
+func (s *Signature) Verify(ctx context.Context, env map[string]string, sf SignedFielder, verifier Verifier, opts ...VerifyOption) error {
+	var o verifyOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(s.SignedFields) == 0 {
+		return fmt.Errorf("signature covers no fields")
+	}
+
+	envelope, err := envelopeFor(s.Envelope)
+	if err != nil {
+		return err
+	}
+
+	fields, err := fieldsToVerify(sf, s.SignedFields, env)
+	if err != nil {
+		return fmt.Errorf("computing fields to verify: %w", err)
+	}
+
+	payload := canonicalPayload(fields)
+
+	logDebugSigning(o.debugLogger, fields, s.SignedFields, payload, o.debugIncludeValues)
+
+	verifyCtx := ctx
+	if o.tsaPolicy != nil {
+		if len(s.Timestamp) == 0 {
+			return fmt.Errorf("WithTimestampTrust given but signature has no RFC 3161 timestamp")
+		}
+		genTime, err := verifyTimestampToken(s.Timestamp, []byte(s.Value), o.tsaPolicy)
+		if err != nil {
+			return fmt.Errorf("verifying RFC 3161 timestamp: %w", err)
+		}
+		verifyCtx = withVerifyTime(ctx, genTime)
+	}
+
+	if err := envelope.open(verifyCtx, s.SignedFields, payload, s.Value, verifier); err != nil {
+		return fmt.Errorf("opening %s envelope: %w", envelopeOrDefault(s.Envelope), err)
+	}
+
+	return nil
+}
+
+func base64URLEncode(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func base64URLDecode(s string) ([]byte, error) { return base64.RawURLEncoding.DecodeString(s) }
+
+func errUnknownSignedField(field string) error {
+	return fmt.Errorf("unknown signed field %q", field)
+}
@@ -1,12 +1,16 @@
 package pipeline
 
 import (
+	"context"
+	"crypto"
 	"crypto/elliptic"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -51,19 +55,19 @@ func TestSignVerify(t *testing.T) {
 			name:                           "HMAC-SHA256",
 			generateSigner:                 func(alg jwa.SignatureAlgorithm) (jwk.Key, jwk.Set) { return newSymmetricKeyPair(t, "alpacas", alg) },
 			alg:                            jwa.HS256,
-			expectedDeterministicSignature: "eyJhbGciOiJIUzI1NiIsImtpZCI6IlRlc3RTaWduVmVyaWZ5In0..Xd7udcMRc3Gg236JdiV2vggGrqxAfgfLZdCLUpgAN34",
+			expectedDeterministicSignature: "eyJhbGciOiJIUzI1NiIsImtpZCI6IlRlc3RTaWduVmVyaWZ5IiwiY3JpdCI6WyJzaWduZWRfZmllbGRzIl0sInNpZ25lZF9maWVsZHMiOlsiY29tbWFuZCIsImVudiIsInBsdWdpbnMiXX0..fsyMUQnlXehGgZeGVIYKsJXJ7vfWv97_l-yWkz6BRAg",
 		},
 		{
 			name:                           "HMAC-SHA384",
 			generateSigner:                 func(alg jwa.SignatureAlgorithm) (jwk.Key, jwk.Set) { return newSymmetricKeyPair(t, "alpacas", alg) },
 			alg:                            jwa.HS384,
-			expectedDeterministicSignature: "eyJhbGciOiJIUzM4NCIsImtpZCI6IlRlc3RTaWduVmVyaWZ5In0..g-_B2RO6o_oZjPoM2UyCHDANbPeeqLBUexLRl_MoW7BdpLC7r6mLc0wgRIzJy6ih",
+			expectedDeterministicSignature: "eyJhbGciOiJIUzM4NCIsImtpZCI6IlRlc3RTaWduVmVyaWZ5IiwiY3JpdCI6WyJzaWduZWRfZmllbGRzIl0sInNpZ25lZF9maWVsZHMiOlsiY29tbWFuZCIsImVudiIsInBsdWdpbnMiXX0..WFqVBNzsuupd8QUi5FEgDtMMh7pBA7P9TwrBGe2SQyHDUb6wFmQqCkoFT6RVgM1y",
 		},
 		{
 			name:                           "HMAC-SHA512",
 			generateSigner:                 func(alg jwa.SignatureAlgorithm) (jwk.Key, jwk.Set) { return newSymmetricKeyPair(t, "alpacas", alg) },
 			alg:                            jwa.HS512,
-			expectedDeterministicSignature: "eyJhbGciOiJIUzUxMiIsImtpZCI6IlRlc3RTaWduVmVyaWZ5In0..iW8eaMBrcK7Ehj41DRzgQp3haYBf70JgA_n0C4d_acRZCdVUm-GJv9pdxQ5O0pYd7gJC_wMmaNMkuj4TXqlPvg",
+			expectedDeterministicSignature: "eyJhbGciOiJIUzUxMiIsImtpZCI6IlRlc3RTaWduVmVyaWZ5IiwiY3JpdCI6WyJzaWduZWRfZmllbGRzIl0sInNpZ25lZF9maWVsZHMiOlsiY29tbWFuZCIsImVudiIsInBsdWdpbnMiXX0..8z5P6DH56gSXhdrNG0WUjYiC4sW2N-IdFgJXShnlRp91sQXm2R1b7lhUmJYTwlBXOj2pi2q-8LgkSfBVQVoACg",
 		},
 		{
 			name:           "RSA-PSS 256",
@@ -108,7 +112,7 @@ func TestSignVerify(t *testing.T) {
 			t.Parallel()
 			signer, verifier := tc.generateSigner(tc.alg)
 
-			sig, err := Sign(signEnv, step, signer)
+			sig, err := Sign(context.Background(), signEnv, step, NewJWKSigner(signer))
 			if err != nil {
 				t.Fatalf("Sign(CommandStep, signer) error = %v", err)
 			}
@@ -126,7 +130,7 @@ func TestSignVerify(t *testing.T) {
 				}
 			}
 
-			if err := sig.Verify(verifyEnv, step, verifier); err != nil {
+			if err := sig.Verify(context.Background(), verifyEnv, step, NewJWKVerifier(verifier)); err != nil {
 				t.Errorf("sig.Verify(CommandStep, verifier) = %v", err)
 			}
 		})
@@ -176,7 +180,7 @@ func TestSignConcatenatedFields(t *testing.T) {
 
 	signer, _ := newSymmetricKeyPair(t, "alpacas", jwa.HS256)
 	for _, m := range maps {
-		sig, err := Sign(nil, m, signer)
+		sig, err := Sign(context.Background(), nil, m, NewJWKSigner(signer))
 		if err != nil {
 			t.Fatalf("Sign(%v, pts) error = %v", m, err)
 		}
@@ -199,7 +203,7 @@ func TestUnknownAlgorithm(t *testing.T) {
 	signer, _ := newSymmetricKeyPair(t, "alpacas", jwa.HS256)
 	signer.Set(jwk.AlgorithmKey, "rot13")
 
-	if _, err := Sign(nil, &CommandStep{Command: "llamas"}, signer); err == nil {
+	if _, err := Sign(context.Background(), nil, &CommandStep{Command: "llamas"}, NewJWKSigner(signer)); err == nil {
 		t.Errorf("Sign(nil, CommandStep, signer) = %v, want non-nil error", err)
 	}
 }
@@ -216,7 +220,7 @@ func TestVerifyBadSignature(t *testing.T) {
 	}
 
 	_, verifier := newSymmetricKeyPair(t, "alpacas", jwa.HS256)
-	if err := sig.Verify(nil, cs, verifier); err == nil {
+	if err := sig.Verify(context.Background(), nil, cs, NewJWKVerifier(verifier)); err == nil {
 		t.Errorf("sig.Verify(CommandStep, alpacas) = %v, want non-nil error", err)
 	}
 }
@@ -229,7 +233,7 @@ func TestSignUnknownStep(t *testing.T) {
 	}
 
 	signer, _ := newSymmetricKeyPair(t, "alpacas", jwa.HS256)
-	if err := steps.sign(nil, signer); !errors.Is(err, errSigningRefusedUnknownStepType) {
+	if err := steps.sign(context.Background(), nil, NewJWKSigner(signer)); !errors.Is(err, errSigningRefusedUnknownStepType) {
 		t.Errorf("steps.sign(signer) = %v, want %v", err, errSigningRefusedUnknownStepType)
 	}
 }
@@ -299,12 +303,12 @@ func TestSignVerifyEnv(t *testing.T) {
 			t.Parallel()
 			signer, verifier := newSymmetricKeyPair(t, "alpacas", jwa.HS256)
 
-			sig, err := Sign(tc.pipelineEnv, tc.step, signer)
+			sig, err := Sign(context.Background(), tc.pipelineEnv, tc.step, NewJWKSigner(signer))
 			if err != nil {
 				t.Fatalf("Sign(CommandStep, signer) error = %v", err)
 			}
 
-			if err := sig.Verify(tc.verifyEnv, tc.step, verifier); err != nil {
+			if err := sig.Verify(context.Background(), tc.verifyEnv, tc.step, NewJWKVerifier(verifier)); err != nil {
 				t.Errorf("sig.Verify(CommandStep, verifier) = %v", err)
 			}
 		})
@@ -342,12 +346,110 @@ func TestSignatureStability(t *testing.T) {
 
 	signer, verifier := newECKeyPair(t, jwa.ES256, elliptic.P256())
 
-	sig, err := Sign(env, step, signer)
+	sig, err := Sign(context.Background(), env, step, NewJWKSigner(signer))
 	if err != nil {
 		t.Fatalf("Sign(env, CommandStep, signer) error = %v", err)
 	}
 
-	if err := sig.Verify(env, step, verifier); err != nil {
+	if err := sig.Verify(context.Background(), env, step, NewJWKVerifier(verifier)); err != nil {
 		t.Errorf("sig.Verify(env, CommandStep, verifier) = %v", err)
 	}
 }
+
+func TestSignVerifyTimestampSurvivesCertRotation(t *testing.T) {
+	// The leaf certificate is already expired by the time this test runs,
+	// simulating a signer whose certificate has since been rotated out.
+	// It was valid, however, around signingTime.
+	signingTime := time.Now().Add(-36 * time.Hour)
+	chain, leafKey := testCertChain(t, time.Now().Add(-48*time.Hour), time.Now().Add(-24*time.Hour), nil)
+
+	signer, err := NewCertChainSigner(leafKey, chain, jwa.ES256)
+	if err != nil {
+		t.Fatalf("NewCertChainSigner() error = %v", err)
+	}
+
+	tsa := newFakeTSAClient(t, signingTime)
+	step := &CommandStep{Command: "llamas"}
+
+	sig, err := Sign(context.Background(), nil, step, signer, WithTimestamp(tsa, crypto.SHA256))
+	if err != nil {
+		t.Fatalf("Sign(CommandStep, CertChainSigner, WithTimestamp) error = %v", err)
+	}
+	if len(sig.Timestamp) == 0 {
+		t.Fatalf("Signature.Timestamp is empty, want an RFC 3161 token")
+	}
+
+	verifier := &CertChainVerifier{Policy: TrustPolicy{Roots: trustPoolOf(chain[len(chain)-1])}}
+
+	if err := sig.Verify(context.Background(), nil, step, verifier); err == nil {
+		t.Errorf("sig.Verify(CommandStep, CertChainVerifier) with expired leaf and no timestamp trust = nil, want non-nil error")
+	}
+
+	tsaPolicy := TimestampTrustPolicy{Roots: trustPoolOf(tsa.cert)}
+	if err := sig.Verify(context.Background(), nil, step, verifier, WithTimestampTrust(tsaPolicy)); err != nil {
+		t.Errorf("sig.Verify(CommandStep, CertChainVerifier, WithTimestampTrust) = %v, want nil", err)
+	}
+}
+
+// capturingLogger implements Logger, recording every formatted line it's
+// given so tests can assert on debug-signing output.
+type capturingLogger struct {
+	lines []string
+}
+
+func (l *capturingLogger) Printf(format string, v ...any) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *capturingLogger) String() string { return strings.Join(l.lines, "\n") }
+
+func TestDebugSigningRedactsValuesByDefault(t *testing.T) {
+	step := &CommandStep{
+		Command: "llamas",
+		Env:     map[string]string{"DEPLOY_TOKEN": "super-secret-value"},
+	}
+	signer, verifier := newSymmetricKeyPair(t, "alpacas", jwa.HS256)
+
+	var signLog capturingLogger
+	sig, err := Sign(context.Background(), nil, step, NewJWKSigner(signer), WithDebugSigning(&signLog))
+	if err != nil {
+		t.Fatalf("Sign(CommandStep, signer, WithDebugSigning) error = %v", err)
+	}
+
+	for _, field := range sig.SignedFields {
+		sum := sha256.Sum256([]byte(mustSignedFieldValue(t, step, field)))
+		want := fmt.Sprintf("%x", sum)
+		if !strings.Contains(signLog.String(), want) {
+			t.Errorf("debug log missing sha256 of field %q; log = %s", field, signLog.String())
+		}
+	}
+
+	if strings.Contains(signLog.String(), "super-secret-value") {
+		t.Errorf("debug log leaked a field value by default; log = %s", signLog.String())
+	}
+
+	var verifyLog capturingLogger
+	if err := sig.Verify(context.Background(), nil, step, NewJWKVerifier(verifier), WithDebugVerifying(&verifyLog)); err != nil {
+		t.Errorf("sig.Verify(CommandStep, verifier, WithDebugVerifying) = %v", err)
+	}
+	if strings.Contains(verifyLog.String(), "super-secret-value") {
+		t.Errorf("verify debug log leaked a field value by default; log = %s", verifyLog.String())
+	}
+
+	var includeLog capturingLogger
+	if _, err := Sign(context.Background(), nil, step, NewJWKSigner(signer), WithDebugSigning(&includeLog), WithDebugSigningIncludeValues()); err != nil {
+		t.Fatalf("Sign(CommandStep, signer, WithDebugSigning, WithDebugSigningIncludeValues) error = %v", err)
+	}
+	if !strings.Contains(includeLog.String(), "super-secret-value") {
+		t.Errorf("debug log with WithDebugSigningIncludeValues did not include a field value; log = %s", includeLog.String())
+	}
+}
+
+func mustSignedFieldValue(t *testing.T, step *CommandStep, field string) string {
+	t.Helper()
+	values, err := step.ValuesForFields([]string{field})
+	if err != nil {
+		t.Fatalf("step.ValuesForFields(%q) error = %v", field, err)
+	}
+	return values[field]
+}
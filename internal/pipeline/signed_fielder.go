@@ -0,0 +1,84 @@
+package pipeline
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// SignedFielder describes types that can be signed and have signatures
+// verified over a subset of their fields.
+//
+// SignedFields returns the default set of fields to sign, and their
+// canonical values. ValuesForFields is the verification-time counterpart: it
+// looks up the values for an arbitrary (backend-supplied) set of field names,
+// which may not match what SignedFields would have chosen (for example,
+// because the agent and the backend have different versions of the step
+// schema).
+type SignedFielder interface {
+	SignedFields() (map[string]string, error)
+	ValuesForFields(fields []string) (map[string]string, error)
+}
+
+// EnvSignedFielder is implemented by SignedFielders whose "env" field depends
+// on the pipeline-level environment supplied to Sign/Verify. For the keys the
+// step's own env declares, the pipeline env's values override the step's
+// (pipeline values win) before the "env" field is computed; keys the step
+// doesn't declare are not included in the "env" field.
+type EnvSignedFielder interface {
+	SignedFielder
+
+	signedFieldsWithEnv(env map[string]string) (map[string]string, error)
+	valuesForFieldsWithEnv(fields []string, env map[string]string) (map[string]string, error)
+}
+
+// fieldsToSign returns the fields (and values) that Sign should compute a
+// signature over, taking the pipeline env into account if sf supports it.
+func fieldsToSign(sf SignedFielder, env map[string]string) (map[string]string, error) {
+	if esf, ok := sf.(EnvSignedFielder); ok {
+		return esf.signedFieldsWithEnv(env)
+	}
+	return sf.SignedFields()
+}
+
+// fieldsToVerify returns the values of fields (as named by a Signature) that
+// Verify should compare against, taking the pipeline/runtime env into account
+// if sf supports it.
+func fieldsToVerify(sf SignedFielder, fields []string, env map[string]string) (map[string]string, error) {
+	if esf, ok := sf.(EnvSignedFielder); ok {
+		return esf.valuesForFieldsWithEnv(fields, env)
+	}
+	return sf.ValuesForFields(fields)
+}
+
+// canonicalPayload deterministically encodes a set of named fields into a
+// byte string suitable for signing. Field names are sorted so the encoding
+// doesn't depend on map iteration order, and each name/value is length
+// prefixed so that, for example, signing {"foo": "bar", "qux": "zap"} can
+// never produce the same payload as signing {"foo": "barquxzap"}.
+func canonicalPayload(fields map[string]string) []byte {
+	names := sortedFieldNames(fields)
+
+	var buf bytes.Buffer
+	for _, name := range names {
+		writeLengthPrefixed(&buf, []byte(name))
+		writeLengthPrefixed(&buf, []byte(fields[name]))
+	}
+	return buf.Bytes()
+}
+
+func sortedFieldNames(fields map[string]string) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeLengthPrefixed(buf *bytes.Buffer, b []byte) {
+	var length [4]byte
+	binary.LittleEndian.PutUint32(length[:], uint32(len(b)))
+	buf.Write(length[:])
+	buf.Write(b)
+}
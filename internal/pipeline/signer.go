@@ -0,0 +1,127 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// Signer is anything that can produce a signature over an (already
+// canonicalised) payload. Sign calls it once per Signature computed; it
+// never needs access to the key material itself, which lets a Signer be
+// backed by something that never exposes the private key to the agent
+// process at all (a KMS, an HSM, a signing plugin running out of process).
+type Signer interface {
+	// Algorithm reports the signature algorithm this Signer produces, so
+	// that envelope headers can be built (and, for envelopes that sign the
+	// header along with the payload, included in the signing input) without
+	// a round trip to find out.
+	Algorithm() jwa.SignatureAlgorithm
+
+	// KeyID reports the key ID that should be recorded alongside any
+	// signature this Signer produces.
+	KeyID() string
+
+	// SignPayload signs payload and returns the raw signature bytes.
+	SignPayload(ctx context.Context, payload []byte) (sig []byte, err error)
+}
+
+// Verifier is anything that can check a signature over an (already
+// canonicalised) payload, keyed by algorithm and key ID.
+type Verifier interface {
+	VerifyPayload(ctx context.Context, payload, sig []byte, alg jwa.SignatureAlgorithm, kid string) error
+}
+
+// NewJWKSigner adapts a jwk.Key (the signing primitive pipeline.Sign has
+// always used) to the Signer interface.
+func NewJWKSigner(key jwk.Key) Signer { return jwkSigner{key} }
+
+// NewJWKVerifier adapts a jwk.Set (the verification primitive
+// Signature.Verify has always used) to the Verifier interface.
+func NewJWKVerifier(set jwk.Set) Verifier { return jwkVerifier{set} }
+
+type jwkSigner struct{ key jwk.Key }
+
+func (a jwkSigner) Algorithm() jwa.SignatureAlgorithm {
+	alg, _ := signatureAlgorithm(a.key)
+	return alg
+}
+
+func (a jwkSigner) KeyID() string { return a.key.KeyID() }
+
+func (a jwkSigner) SignPayload(_ context.Context, payload []byte) ([]byte, error) {
+	sigAlg, err := signatureAlgorithm(a.key)
+	if err != nil {
+		return nil, err
+	}
+
+	// jwx doesn't expose "just sign these bytes with this key" directly, so
+	// we ask it for a minimal detached compact JWS and keep only the
+	// signature part - the raw bytes are all a Signer is expected to
+	// produce, since the envelope around them is Sign's responsibility, not
+	// the signing primitive's.
+	compact, err := jws.Sign(nil, jws.WithKey(sigAlg, a.key), jws.WithDetachedPayload(payload))
+	if err != nil {
+		return nil, fmt.Errorf("signing payload: %w", err)
+	}
+
+	return rawSignatureFromCompactJWS(compact)
+}
+
+type jwkVerifier struct{ set jwk.Set }
+
+func (a jwkVerifier) VerifyPayload(_ context.Context, payload, sig []byte, alg jwa.SignatureAlgorithm, kid string) error {
+	compact, err := compactJWSFromRawSignature(alg, kid, sig)
+	if err != nil {
+		return err
+	}
+
+	_, err = jws.Verify(compact, jws.WithKeySet(a.set), jws.WithDetachedPayload(payload))
+	return err
+}
+
+func signatureAlgorithm(key jwk.Key) (jwa.SignatureAlgorithm, error) {
+	sigAlg, ok := key.Algorithm().(jwa.SignatureAlgorithm)
+	if !ok {
+		return "", fmt.Errorf("key %q has no signature algorithm", key.KeyID())
+	}
+
+	return sigAlg, nil
+}
+
+// rawSignatureFromCompactJWS extracts the raw signature bytes from a
+// detached compact JWS of the form "header..signature".
+func rawSignatureFromCompactJWS(compact []byte) ([]byte, error) {
+	parts := strings.Split(string(compact), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed compact JWS: want 3 parts, got %d", len(parts))
+	}
+
+	return base64URLDecode(parts[2])
+}
+
+// compactJWSFromRawSignature rebuilds a minimal detached compact JWS around
+// a raw signature, so that jws.Verify can check it against a jwk.Set.
+func compactJWSFromRawSignature(alg jwa.SignatureAlgorithm, kid string, sig []byte) ([]byte, error) {
+	headers := jws.NewHeaders()
+	if err := headers.Set(jws.AlgorithmKey, alg); err != nil {
+		return nil, fmt.Errorf("setting alg header: %w", err)
+	}
+	if kid != "" {
+		if err := headers.Set(jws.KeyIDKey, kid); err != nil {
+			return nil, fmt.Errorf("setting kid header: %w", err)
+		}
+	}
+
+	headerJSON, err := json.Marshal(headers)
+	if err != nil {
+		return nil, fmt.Errorf("encoding headers: %w", err)
+	}
+
+	return []byte(base64URLEncode(headerJSON) + ".." + base64URLEncode(sig)), nil
+}
@@ -0,0 +1,111 @@
+package signer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+// fakeKMSClient signs/verifies with a local ECDSA key, standing in for the
+// AWS KMS service so AWSKMS can be exercised without real AWS credentials.
+// It rejects anything but a DIGEST message, the same as real KMS would
+// reject a RAW message over 4096 bytes: AWSKMS must hash the payload itself
+// rather than sending it raw.
+type fakeKMSClient struct {
+	key *ecdsa.PrivateKey
+}
+
+func (f *fakeKMSClient) Sign(_ context.Context, in *kms.SignInput, _ ...func(*kms.Options)) (*kms.SignOutput, error) {
+	if in.MessageType != types.MessageTypeDigest {
+		return nil, fmt.Errorf("fakeKMSClient: want MessageType DIGEST, got %v", in.MessageType)
+	}
+	sig, err := ecdsa.SignASN1(rand.Reader, f.key, in.Message)
+	if err != nil {
+		return nil, err
+	}
+	return &kms.SignOutput{Signature: sig, SigningAlgorithm: in.SigningAlgorithm}, nil
+}
+
+func (f *fakeKMSClient) Verify(_ context.Context, in *kms.VerifyInput, _ ...func(*kms.Options)) (*kms.VerifyOutput, error) {
+	if in.MessageType != types.MessageTypeDigest {
+		return nil, fmt.Errorf("fakeKMSClient: want MessageType DIGEST, got %v", in.MessageType)
+	}
+	valid := ecdsa.VerifyASN1(&f.key.PublicKey, in.Message, in.Signature)
+	return &kms.VerifyOutput{SignatureValid: valid}, nil
+}
+
+func TestAWSKMSSignVerify(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	client := &fakeKMSClient{key: key}
+
+	s, err := NewAWSKMS(client, "alias/test-key", jwa.ES256)
+	if err != nil {
+		t.Fatalf("NewAWSKMS() error = %v", err)
+	}
+
+	if s.KeyID() != "alias/test-key" {
+		t.Errorf("KeyID() = %q, want %q", s.KeyID(), "alias/test-key")
+	}
+	if s.Algorithm() != jwa.ES256 {
+		t.Errorf("Algorithm() = %v, want %v", s.Algorithm(), jwa.ES256)
+	}
+
+	payload := []byte("the canonical payload")
+
+	sig, err := s.SignPayload(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("SignPayload() error = %v", err)
+	}
+
+	if err := s.VerifyPayload(context.Background(), payload, sig, jwa.ES256, s.KeyID()); err != nil {
+		t.Errorf("VerifyPayload() error = %v", err)
+	}
+
+	if err := s.VerifyPayload(context.Background(), []byte("tampered payload"), sig, jwa.ES256, s.KeyID()); err == nil {
+		t.Error("VerifyPayload() with tampered payload = nil, want error")
+	}
+}
+
+func TestAWSKMSSignVerifyLargePayload(t *testing.T) {
+	// KMS caps RAW messages at 4096 bytes; a realistic envelope (protected
+	// header plus canonical payload) can exceed that, so AWSKMS must send a
+	// DIGEST instead - fakeKMSClient rejects anything else.
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey() error = %v", err)
+	}
+	client := &fakeKMSClient{key: key}
+
+	s, err := NewAWSKMS(client, "alias/test-key", jwa.ES256)
+	if err != nil {
+		t.Fatalf("NewAWSKMS() error = %v", err)
+	}
+
+	payload := []byte(strings.Repeat("a", 8192))
+
+	sig, err := s.SignPayload(context.Background(), payload)
+	if err != nil {
+		t.Fatalf("SignPayload() error = %v", err)
+	}
+
+	if err := s.VerifyPayload(context.Background(), payload, sig, jwa.ES256, s.KeyID()); err != nil {
+		t.Errorf("VerifyPayload() error = %v", err)
+	}
+}
+
+func TestNewAWSKMSUnsupportedAlgorithm(t *testing.T) {
+	if _, err := NewAWSKMS(&fakeKMSClient{}, "alias/test-key", jwa.HS256); err == nil {
+		t.Error("NewAWSKMS(HS256) = nil error, want non-nil (KMS asymmetric keys only)")
+	}
+}
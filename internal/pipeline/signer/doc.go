@@ -0,0 +1,8 @@
+// Package signer provides pipeline.Signer and pipeline.Verifier
+// implementations backed by remote key-management services, so that the
+// agent never has to hold raw signing key material: AWSKMS and GCPKMS talk
+// to their respective cloud KMS APIs, and PKCS11 talks to a local or
+// network-attached HSM. Each adapter signs/verifies the exact canonical
+// payload bytes pipeline.Sign/Signature.Verify already compute - the KMS or
+// HSM never sees step internals, only an opaque blob.
+package signer
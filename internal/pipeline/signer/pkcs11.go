@@ -0,0 +1,184 @@
+package signer
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/miekg/pkcs11"
+)
+
+// PKCS11 signs and verifies pipeline step payloads using a key held in a
+// PKCS#11 token (an HSM, or a software token such as SoftHSM for testing).
+// The private key never leaves the token; only digests cross the PKCS#11
+// API boundary.
+type PKCS11 struct {
+	ctx        *pkcs11.Ctx
+	session    pkcs11.SessionHandle
+	privateKey pkcs11.ObjectHandle
+	publicKey  pkcs11.ObjectHandle
+	alg        jwa.SignatureAlgorithm
+	kid        string
+
+	mu sync.Mutex // PKCS#11 sessions are not safe for concurrent Sign/Verify
+}
+
+// NewPKCS11 opens a session against the token in slot, logs in with pin, and
+// returns a signer/verifier for the given key pair. privateKeyLabel and
+// publicKeyLabel are the CKA_LABEL of the objects to use (as configured on
+// the token).
+func NewPKCS11(modulePath string, slot uint, pin, privateKeyLabel, publicKeyLabel string, alg jwa.SignatureAlgorithm) (*PKCS11, error) {
+	if _, err := pkcs11MechanismFor(alg); err != nil {
+		return nil, err
+	}
+
+	ctx := pkcs11.New(modulePath)
+	if ctx == nil {
+		return nil, fmt.Errorf("pkcs11: loading module %q failed", modulePath)
+	}
+	if err := ctx.Initialize(); err != nil {
+		return nil, fmt.Errorf("pkcs11: initializing module: %w", err)
+	}
+
+	session, err := ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION|pkcs11.CKF_RW_SESSION)
+	if err != nil {
+		ctx.Destroy()
+		return nil, fmt.Errorf("pkcs11: opening session on slot %d: %w", slot, err)
+	}
+	if err := ctx.Login(session, pkcs11.CKU_USER, pin); err != nil {
+		ctx.CloseSession(session)
+		ctx.Destroy()
+		return nil, fmt.Errorf("pkcs11: login: %w", err)
+	}
+
+	priv, err := findObject(ctx, session, pkcs11.CKO_PRIVATE_KEY, privateKeyLabel)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := findObject(ctx, session, pkcs11.CKO_PUBLIC_KEY, publicKeyLabel)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PKCS11{ctx: ctx, session: session, privateKey: priv, publicKey: pub, alg: alg, kid: privateKeyLabel}, nil
+}
+
+// Algorithm implements pipeline.Signer.
+func (p *PKCS11) Algorithm() jwa.SignatureAlgorithm { return p.alg }
+
+// KeyID implements pipeline.Signer.
+func (p *PKCS11) KeyID() string { return p.kid }
+
+// Close logs out, closes the session, and finalizes the PKCS#11 module.
+func (p *PKCS11) Close() error {
+	p.ctx.Logout(p.session)
+	p.ctx.CloseSession(p.session)
+	p.ctx.Finalize()
+	p.ctx.Destroy()
+	return nil
+}
+
+// SignPayload implements pipeline.Signer.
+func (p *PKCS11) SignPayload(_ context.Context, payload []byte) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	mech, err := pkcs11MechanismFor(p.alg)
+	if err != nil {
+		return nil, err
+	}
+
+	digest, err := hashPayload(p.alg, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.ctx.SignInit(p.session, []*pkcs11.Mechanism{mech}, p.privateKey); err != nil {
+		return nil, fmt.Errorf("pkcs11: SignInit: %w", err)
+	}
+	sig, err := p.ctx.Sign(p.session, digest)
+	if err != nil {
+		return nil, fmt.Errorf("pkcs11: Sign: %w", err)
+	}
+
+	return sig, nil
+}
+
+// VerifyPayload implements pipeline.Verifier.
+func (p *PKCS11) VerifyPayload(_ context.Context, payload, sig []byte, alg jwa.SignatureAlgorithm, _ string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	mech, err := pkcs11MechanismFor(alg)
+	if err != nil {
+		return err
+	}
+
+	digest, err := hashPayload(alg, payload)
+	if err != nil {
+		return err
+	}
+
+	if err := p.ctx.VerifyInit(p.session, []*pkcs11.Mechanism{mech}, p.publicKey); err != nil {
+		return fmt.Errorf("pkcs11: VerifyInit: %w", err)
+	}
+	if err := p.ctx.Verify(p.session, digest, sig); err != nil {
+		return fmt.Errorf("pkcs11: signature not valid: %w", err)
+	}
+
+	return nil
+}
+
+func findObject(ctx *pkcs11.Ctx, session pkcs11.SessionHandle, class uint, label string) (pkcs11.ObjectHandle, error) {
+	tmpl := []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_CLASS, class),
+		pkcs11.NewAttribute(pkcs11.CKA_LABEL, label),
+	}
+	if err := ctx.FindObjectsInit(session, tmpl); err != nil {
+		return 0, fmt.Errorf("pkcs11: FindObjectsInit(%q): %w", label, err)
+	}
+	defer ctx.FindObjectsFinal(session)
+
+	objs, _, err := ctx.FindObjects(session, 1)
+	if err != nil {
+		return 0, fmt.Errorf("pkcs11: FindObjects(%q): %w", label, err)
+	}
+	if len(objs) == 0 {
+		return 0, fmt.Errorf("pkcs11: no object with label %q", label)
+	}
+
+	return objs[0], nil
+}
+
+func pkcs11MechanismFor(alg jwa.SignatureAlgorithm) (*pkcs11.Mechanism, error) {
+	switch alg {
+	case jwa.ES256:
+		return pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil), nil
+	case jwa.ES384:
+		return pkcs11.NewMechanism(pkcs11.CKM_ECDSA, nil), nil
+	case jwa.PS256:
+		return pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_PSS, pkcs11.NewPSSParams(pkcs11.CKM_SHA256, pkcs11.CKG_MGF1_SHA256, 32)), nil
+	case jwa.PS384:
+		return pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_PSS, pkcs11.NewPSSParams(pkcs11.CKM_SHA384, pkcs11.CKG_MGF1_SHA384, 48)), nil
+	default:
+		return nil, fmt.Errorf("pkcs11: unsupported algorithm %v", alg)
+	}
+}
+
+func hashPayload(alg jwa.SignatureAlgorithm, payload []byte) ([]byte, error) {
+	var h hash.Hash
+	switch alg {
+	case jwa.ES256, jwa.PS256:
+		h = sha256.New()
+	case jwa.ES384, jwa.PS384:
+		h = sha512.New384()
+	default:
+		return nil, fmt.Errorf("pkcs11: unsupported algorithm %v", alg)
+	}
+	h.Write(payload)
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,41 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+)
+
+// Step is implemented by all step types that can appear in a pipeline.
+type Step interface {
+	stepTag()
+}
+
+// Steps is an ordered list of steps, as they would appear in a pipeline.
+type Steps []Step
+
+// errSigningRefusedUnknownStepType is returned by Steps.sign when it
+// encounters a step that it doesn't know how to sign. We refuse to sign
+// pipelines containing such steps rather than silently skip them, since that
+// could let an unsigned step slip past a backend that only checks for the
+// presence of a signature on the steps it recognises.
+var errSigningRefusedUnknownStepType = errors.New("pipeline signing: refusing to sign a step of unknown type")
+
+// sign signs every step in s with signer, attaching the resulting signature to
+// each step. It refuses (and returns errSigningRefusedUnknownStepType) if any
+// step is of a type it doesn't know how to sign.
+func (s Steps) sign(ctx context.Context, env map[string]string, signer Signer) error {
+	for _, step := range s {
+		switch step := step.(type) {
+		case *CommandStep:
+			sig, err := Sign(ctx, env, step, signer)
+			if err != nil {
+				return err
+			}
+			step.Signature = sig
+
+		default:
+			return errSigningRefusedUnknownStepType
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/digitorus/timestamp"
+)
+
+// TSAClient requests an RFC 3161 timestamp over a signature value from a
+// Time-Stamping Authority, returning the raw bytes of its TimeStampResp
+// (RFC 3161 §2.4.2) for Signature.Timestamp.
+type TSAClient interface {
+	Timestamp(ctx context.Context, sig []byte, hash crypto.Hash) (token []byte, err error)
+}
+
+// HTTPTSAClient is a TSAClient that speaks the RFC 3161 HTTP binding
+// (RFC 3161 §3.4) to a TSA at URL.
+type HTTPTSAClient struct {
+	URL        string
+	HTTPClient *http.Client
+}
+
+// NewHTTPTSAClient returns an HTTPTSAClient for the TSA at url, using
+// http.DefaultClient.
+func NewHTTPTSAClient(url string) *HTTPTSAClient {
+	return &HTTPTSAClient{URL: url}
+}
+
+// Timestamp implements TSAClient.
+func (c *HTTPTSAClient) Timestamp(ctx context.Context, sig []byte, hash crypto.Hash) ([]byte, error) {
+	reqBytes, err := timestamp.CreateRequest(bytes.NewReader(sig), &timestamp.RequestOptions{
+		Hash:         hash,
+		Certificates: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("building RFC 3161 request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(reqBytes))
+	if err != nil {
+		return nil, fmt.Errorf("building TSA HTTP request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/timestamp-query")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("requesting timestamp from %s: %w", c.URL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response from %s: %w", c.URL, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("TSA %s returned status %s", c.URL, resp.Status)
+	}
+
+	return body, nil
+}
+
+// TimestampTrustPolicy describes what Signature.Verify should require of an
+// RFC 3161 Timestamp before trusting its genTime as the reference time for
+// validating an x5c signing certificate chain.
+type TimestampTrustPolicy struct {
+	// Roots is the set of CA certificates the TSA's own certificate chain
+	// must terminate at.
+	Roots *x509.CertPool
+}
+
+// verifyTimestampToken parses token, checks it genuinely covers
+// signatureValue, and validates the TSA's certificate chain against
+// policy.Roots, returning the timestamp's genTime on success.
+func verifyTimestampToken(token, signatureValue []byte, policy *TimestampTrustPolicy) (time.Time, error) {
+	ts, err := timestamp.ParseResponse(token)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing RFC 3161 response: %w", err)
+	}
+
+	digest := hashSum(ts.HashAlgorithm, signatureValue)
+	if !bytes.Equal(digest, ts.HashedMessage) {
+		return time.Time{}, fmt.Errorf("timestamp token covers a different signature value")
+	}
+
+	if len(ts.Certificates) == 0 {
+		return time.Time{}, fmt.Errorf("timestamp token carries no TSA certificate chain")
+	}
+
+	tsaLeaf := ts.Certificates[0]
+	if !hasExtKeyUsage(tsaLeaf, x509.ExtKeyUsageTimeStamping) {
+		return time.Time{}, fmt.Errorf("TSA certificate %s is missing the timeStamping extended key usage", tsaLeaf.Subject)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range ts.Certificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := tsaLeaf.Verify(x509.VerifyOptions{
+		Roots:         policy.Roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		CurrentTime:   ts.Time,
+	}); err != nil {
+		return time.Time{}, fmt.Errorf("TSA certificate chain does not verify to a trusted root: %w", err)
+	}
+
+	return ts.Time, nil
+}
+
+func hasExtKeyUsage(cert *x509.Certificate, eku x509.ExtKeyUsage) bool {
+	for _, u := range cert.ExtKeyUsage {
+		if u == eku {
+			return true
+		}
+	}
+	return false
+}
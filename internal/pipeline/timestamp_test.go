@@ -0,0 +1,89 @@
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/digitorus/timestamp"
+)
+
+// fakeTSAClient is an in-memory TSAClient: it signs RFC 3161 requests
+// itself with a throwaway, self-signed TSA certificate, instead of calling
+// out over HTTP, so tests can run offline and control the timestamp's
+// genTime directly.
+type fakeTSAClient struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	// genTime is the time fakeTSAClient attests to in every response,
+	// which need not be the real current time - tests use this to
+	// simulate "the signature was made while the cert was still valid".
+	genTime time.Time
+}
+
+func newFakeTSAClient(t *testing.T, genTime time.Time) *fakeTSAClient {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey(tsa) error = %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: t.Name() + " TSA"},
+		// verifyTimestampToken validates this certificate as of genTime
+		// (not time.Now()), so the cert must already be valid there - not
+		// just valid "recently" relative to when the test runs.
+		NotBefore:             genTime.Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageTimeStamping},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate(tsa) error = %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate(tsa) error = %v", err)
+	}
+
+	return &fakeTSAClient{cert: cert, key: key, genTime: genTime}
+}
+
+// Timestamp implements TSAClient.
+func (f *fakeTSAClient) Timestamp(_ context.Context, sig []byte, hash crypto.Hash) ([]byte, error) {
+	reqBytes, err := timestamp.CreateRequest(bytes.NewReader(sig), &timestamp.RequestOptions{
+		Hash:         hash,
+		Certificates: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := timestamp.ParseRequest(reqBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ts := timestamp.Timestamp{
+		HashAlgorithm:     req.HashAlgorithm,
+		HashedMessage:     req.HashedMessage,
+		Time:              f.genTime,
+		SerialNumber:      big.NewInt(1),
+		Certificates:      []*x509.Certificate{f.cert},
+		AddTSACertificate: req.Certificates,
+	}
+
+	return ts.CreateResponse(f.cert, f.key)
+}
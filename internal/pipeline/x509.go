@@ -0,0 +1,273 @@
+package pipeline
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+// TrustPolicy describes what Verify should require of an x5c certificate
+// chain before trusting the leaf certificate's public key to check a
+// signature, instead of (or as well as) looking the key up by kid.
+type TrustPolicy struct {
+	// Roots is the set of CA certificates the chain must terminate at.
+	Roots *x509.CertPool
+	// RequiredEKUs, if non-empty, lists extended key usage OIDs the leaf
+	// certificate must carry - e.g. an org-reserved "buildkite pipeline
+	// signing" EKU - in addition to the standard chain validation Verify
+	// already performs.
+	RequiredEKUs []asn1.ObjectIdentifier
+	// RequireX5C rejects signatures that don't carry an x5c chain at all,
+	// for policies that have fully migrated off bare JWK/kid trust. A plain
+	// CertChainVerifier already has no other way to verify a signature, so
+	// this is always effectively true for it; the field exists for
+	// verifiers that also know how to fall back to a keyset lookup.
+	RequireX5C bool
+}
+
+// verifyTimeKey is the context key withVerifyTime/verifyTimeFrom use to pass
+// a reference time for certificate chain validation down through ctx,
+// rather than threading it through every Verifier/envelope call - this is
+// cross-cutting and only Signature.Verify ever needs to set it.
+type verifyTimeKey struct{}
+
+// withVerifyTime returns a context that makes CertChainVerifier validate a
+// chain as of t instead of time.Now().
+func withVerifyTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, verifyTimeKey{}, t)
+}
+
+func verifyTimeFrom(ctx context.Context) time.Time {
+	if t, ok := ctx.Value(verifyTimeKey{}).(time.Time); ok {
+		return t
+	}
+	return time.Now()
+}
+
+// CertificateChainSigner is implemented by Signers that sign with a
+// certificate chain rather than a bare key: envelopes that support it
+// (currently EnvelopeJWS) embed the chain in an x5c header so a verifier
+// can validate it against a TrustPolicy instead of needing the signing key
+// distributed out of band.
+type CertificateChainSigner interface {
+	Signer
+	CertificateChain() []*x509.Certificate
+}
+
+// CertificateChainVerifier is implemented by Verifiers that can validate an
+// x5c chain carried in an envelope's header against a TrustPolicy.
+type CertificateChainVerifier interface {
+	Verifier
+	VerifyCertificateChain(ctx context.Context, chain []*x509.Certificate) error
+}
+
+// CertChainSigner signs using an X.509 certificate chain: organizations can
+// rotate signing keys by issuing new leaf certificates from an offline root
+// rather than redistributing a JWKS.
+type CertChainSigner struct {
+	key   crypto.Signer
+	chain []*x509.Certificate
+	alg   jwa.SignatureAlgorithm
+}
+
+// NewCertChainSigner returns a Signer backed by key and chain (leaf
+// certificate first, then zero or more intermediates; the root is optional
+// and, if present, ignored by verifiers in favour of their own TrustPolicy
+// roots). The leaf certificate must have the digitalSignature key usage.
+func NewCertChainSigner(key crypto.Signer, chain []*x509.Certificate, alg jwa.SignatureAlgorithm) (*CertChainSigner, error) {
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("pipeline: certificate chain must have at least one (leaf) certificate")
+	}
+	if chain[0].KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return nil, fmt.Errorf("pipeline: leaf certificate %s does not have the digitalSignature key usage", chain[0].Subject)
+	}
+	if _, err := hashForAlgorithm(alg); err != nil && alg != jwa.EdDSA {
+		return nil, err
+	}
+
+	return &CertChainSigner{key: key, chain: chain, alg: alg}, nil
+}
+
+// Algorithm implements Signer.
+func (c *CertChainSigner) Algorithm() jwa.SignatureAlgorithm { return c.alg }
+
+// KeyID implements Signer, reporting the SHA-256 thumbprint of the leaf
+// certificate (the same value envelopes carry as x5t#S256).
+func (c *CertChainSigner) KeyID() string { return x5tS256(c.chain[0]) }
+
+// CertificateChain implements CertificateChainSigner.
+func (c *CertChainSigner) CertificateChain() []*x509.Certificate { return c.chain }
+
+// SignPayload implements Signer.
+func (c *CertChainSigner) SignPayload(_ context.Context, payload []byte) ([]byte, error) {
+	return signRaw(c.key, c.alg, payload)
+}
+
+// CertChainVerifier verifies signatures whose envelope carries an x5c
+// chain, by validating the chain against Policy and then checking the
+// signature against the leaf certificate's public key. It has no use for a
+// bare kid with no chain, and rejects those.
+type CertChainVerifier struct {
+	Policy TrustPolicy
+}
+
+// VerifyCertificateChain implements CertificateChainVerifier. It validates
+// chain as of time.Now(), unless ctx carries a different reference time (as
+// Signature.Verify arranges via withVerifyTime when an RFC 3161 timestamp
+// has established the chain was valid at a specific point in the past).
+func (v *CertChainVerifier) VerifyCertificateChain(ctx context.Context, chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("pipeline: empty certificate chain")
+	}
+	leaf := chain[0]
+	now := verifyTimeFrom(ctx)
+
+	for _, cert := range chain {
+		if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+			return fmt.Errorf("pipeline: certificate %s not valid at %s (valid %s to %s)", cert.Subject, now, cert.NotBefore, cert.NotAfter)
+		}
+	}
+
+	if leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return fmt.Errorf("pipeline: leaf certificate %s does not have the digitalSignature key usage", leaf.Subject)
+	}
+
+	for _, required := range v.Policy.RequiredEKUs {
+		if !hasEKU(leaf, required) {
+			return fmt.Errorf("pipeline: leaf certificate %s is missing required EKU %s", leaf.Subject, required)
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         v.Policy.Roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		// The required-EKU check (if any) is already done above against
+		// v.Policy.RequiredEKUs; without this, Verify defaults to requiring
+		// ExtKeyUsageServerAuth, which a pipeline-signing leaf never has.
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}); err != nil {
+		return fmt.Errorf("pipeline: certificate chain does not verify to a trusted root: %w", err)
+	}
+
+	return nil
+}
+
+// VerifyPayload implements Verifier, for callers that hand a
+// CertChainVerifier to something expecting a plain Verifier. It always
+// fails: a CertChainVerifier has no key to check a bare kid against, only
+// certificate chains - see VerifyCertificateChain.
+func (v *CertChainVerifier) VerifyPayload(_ context.Context, _, _ []byte, _ jwa.SignatureAlgorithm, kid string) error {
+	return fmt.Errorf("pipeline: CertChainVerifier requires an x5c certificate chain in the envelope; got bare kid %q", kid)
+}
+
+func hasEKU(cert *x509.Certificate, oid asn1.ObjectIdentifier) bool {
+	for _, ext := range cert.UnknownExtKeyUsage {
+		if ext.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
+
+func x5tS256(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64URLEncode(sum[:])
+}
+
+// signRaw signs payload with key using alg, pre-hashing it first unless alg
+// is EdDSA (which signs the message directly per RFC 8032).
+func signRaw(key crypto.Signer, alg jwa.SignatureAlgorithm, payload []byte) ([]byte, error) {
+	if alg == jwa.EdDSA {
+		return key.Sign(nil, payload, crypto.Hash(0))
+	}
+
+	hash, err := hashForAlgorithm(alg)
+	if err != nil {
+		return nil, err
+	}
+	digest := hashSum(hash, payload)
+
+	switch alg {
+	case jwa.PS256, jwa.PS384, jwa.PS512:
+		return key.Sign(rand.Reader, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash})
+	case jwa.ES256, jwa.ES384, jwa.ES512:
+		return key.Sign(rand.Reader, digest, hash)
+	default:
+		return nil, fmt.Errorf("pipeline: unsupported algorithm %v for certificate-backed signing", alg)
+	}
+}
+
+// verifyRaw verifies sig over payload using pub, the inverse of signRaw.
+func verifyRaw(pub crypto.PublicKey, alg jwa.SignatureAlgorithm, payload, sig []byte) error {
+	if alg == jwa.EdDSA {
+		edKey, ok := pub.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("pipeline: EdDSA signature but public key is %T", pub)
+		}
+		if !ed25519.Verify(edKey, payload, sig) {
+			return fmt.Errorf("pipeline: EdDSA signature verification failed")
+		}
+		return nil
+	}
+
+	hash, err := hashForAlgorithm(alg)
+	if err != nil {
+		return err
+	}
+	digest := hashSum(hash, payload)
+
+	switch alg {
+	case jwa.PS256, jwa.PS384, jwa.PS512:
+		rsaKey, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("pipeline: RSA-PSS signature but public key is %T", pub)
+		}
+		return rsa.VerifyPSS(rsaKey, hash, digest, sig, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash})
+	case jwa.ES256, jwa.ES384, jwa.ES512:
+		ecKey, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("pipeline: ECDSA signature but public key is %T", pub)
+		}
+		if !ecdsa.VerifyASN1(ecKey, digest, sig) {
+			return fmt.Errorf("pipeline: ECDSA signature verification failed")
+		}
+		return nil
+	default:
+		return fmt.Errorf("pipeline: unsupported algorithm %v for certificate-backed verification", alg)
+	}
+}
+
+func hashForAlgorithm(alg jwa.SignatureAlgorithm) (crypto.Hash, error) {
+	switch alg {
+	case jwa.PS256, jwa.ES256:
+		return crypto.SHA256, nil
+	case jwa.PS384, jwa.ES384:
+		return crypto.SHA384, nil
+	case jwa.PS512, jwa.ES512:
+		return crypto.SHA512, nil
+	default:
+		return 0, fmt.Errorf("pipeline: unsupported algorithm %v", alg)
+	}
+}
+
+func hashSum(h crypto.Hash, payload []byte) []byte {
+	hasher := h.New()
+	hasher.Write(payload)
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,176 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+var testPipelineSigningEKU = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1, 1}
+
+// testCertChain builds a two-certificate chain (leaf first, then root) signed
+// with ECDSA P-256, with leaf validity and EKUs controlled by the test case.
+// The root is valid from a day before the leaf's notBefore, so the chain
+// also validates at a past reference time within the leaf's validity.
+func testCertChain(t *testing.T, notBefore, notAfter time.Time, ekus []asn1.ObjectIdentifier) ([]*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey(root) error = %v", err)
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: t.Name() + " root"},
+		NotBefore:             notBefore.Add(-24 * time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate(root) error = %v", err)
+	}
+	root, err := x509.ParseCertificate(rootDER)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate(root) error = %v", err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey(leaf) error = %v", err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(2),
+		Subject:               pkix.Name{CommonName: t.Name() + " leaf"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		UnknownExtKeyUsage:    ekus,
+		BasicConstraintsValid: true,
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, root, &leafKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate(leaf) error = %v", err)
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate(leaf) error = %v", err)
+	}
+
+	return []*x509.Certificate{leaf, root}, leafKey
+}
+
+func trustPoolOf(certs ...*x509.Certificate) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+	return pool
+}
+
+func TestSignVerifyCertChain(t *testing.T) {
+	chain, leafKey := testCertChain(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), []asn1.ObjectIdentifier{testPipelineSigningEKU})
+
+	signer, err := NewCertChainSigner(leafKey, chain, jwa.ES256)
+	if err != nil {
+		t.Fatalf("NewCertChainSigner() error = %v", err)
+	}
+
+	step := &CommandStep{Command: "llamas"}
+
+	sig, err := Sign(context.Background(), nil, step, signer)
+	if err != nil {
+		t.Fatalf("Sign(CommandStep, CertChainSigner) error = %v", err)
+	}
+
+	verifier := &CertChainVerifier{Policy: TrustPolicy{
+		Roots:        trustPoolOf(chain[len(chain)-1]),
+		RequiredEKUs: []asn1.ObjectIdentifier{testPipelineSigningEKU},
+	}}
+
+	if err := sig.Verify(context.Background(), nil, step, verifier); err != nil {
+		t.Errorf("sig.Verify(CommandStep, CertChainVerifier) = %v, want nil", err)
+	}
+}
+
+func TestVerifyCertChainExpired(t *testing.T) {
+	chain, leafKey := testCertChain(t, time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour), nil)
+
+	signer, err := NewCertChainSigner(leafKey, chain, jwa.ES256)
+	if err != nil {
+		t.Fatalf("NewCertChainSigner() error = %v", err)
+	}
+
+	step := &CommandStep{Command: "llamas"}
+
+	sig, err := Sign(context.Background(), nil, step, signer)
+	if err != nil {
+		t.Fatalf("Sign(CommandStep, CertChainSigner) error = %v", err)
+	}
+
+	verifier := &CertChainVerifier{Policy: TrustPolicy{Roots: trustPoolOf(chain[len(chain)-1])}}
+
+	if err := sig.Verify(context.Background(), nil, step, verifier); err == nil {
+		t.Errorf("sig.Verify(CommandStep, CertChainVerifier) with expired leaf = nil, want non-nil error")
+	}
+}
+
+func TestVerifyCertChainWrongEKU(t *testing.T) {
+	otherEKU := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 99999, 1, 2}
+	chain, leafKey := testCertChain(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), []asn1.ObjectIdentifier{otherEKU})
+
+	signer, err := NewCertChainSigner(leafKey, chain, jwa.ES256)
+	if err != nil {
+		t.Fatalf("NewCertChainSigner() error = %v", err)
+	}
+
+	step := &CommandStep{Command: "llamas"}
+
+	sig, err := Sign(context.Background(), nil, step, signer)
+	if err != nil {
+		t.Fatalf("Sign(CommandStep, CertChainSigner) error = %v", err)
+	}
+
+	verifier := &CertChainVerifier{Policy: TrustPolicy{
+		Roots:        trustPoolOf(chain[len(chain)-1]),
+		RequiredEKUs: []asn1.ObjectIdentifier{testPipelineSigningEKU},
+	}}
+
+	if err := sig.Verify(context.Background(), nil, step, verifier); err == nil {
+		t.Errorf("sig.Verify(CommandStep, CertChainVerifier) with wrong EKU = nil, want non-nil error")
+	}
+}
+
+func TestVerifyCertChainUntrustedRoot(t *testing.T) {
+	chain, leafKey := testCertChain(t, time.Now().Add(-time.Hour), time.Now().Add(time.Hour), nil)
+
+	signer, err := NewCertChainSigner(leafKey, chain, jwa.ES256)
+	if err != nil {
+		t.Fatalf("NewCertChainSigner() error = %v", err)
+	}
+
+	step := &CommandStep{Command: "llamas"}
+
+	sig, err := Sign(context.Background(), nil, step, signer)
+	if err != nil {
+		t.Fatalf("Sign(CommandStep, CertChainSigner) error = %v", err)
+	}
+
+	// An empty pool: the chain's root is never trusted.
+	verifier := &CertChainVerifier{Policy: TrustPolicy{Roots: x509.NewCertPool()}}
+
+	if err := sig.Verify(context.Background(), nil, step, verifier); err == nil {
+		t.Errorf("sig.Verify(CommandStep, CertChainVerifier) with untrusted root = nil, want non-nil error")
+	}
+}